@@ -0,0 +1,95 @@
+package multiprogressbar
+
+import "github.com/schollz/progressbar/v3"
+
+// AddAggregate adds a bar whose Max is the sum of every other currently
+// managed bar's max, and whose current value tracks the sum of their current
+// values. It is resynced after every child bar write, after
+// AddAfter/Remove/Sort/AddAggregate itself, and on an OptionRefreshRate tick.
+// description is handled exactly as in Add; pass "" if sinks don't need one.
+// It is a plain parameter rather than trailing variadic, like Add's, because
+// opts already fills that role.
+// Thread safe. Returns the aggregate bar.
+func (mpb *MultiProgressBar) AddAggregate(description string, opts ...progressbar.Option) *progressbar.ProgressBar {
+	agg := progressbar.NewOptions64(0, opts...)
+
+	mpb.guard.Lock()
+	mpb.aggregates = append(mpb.aggregates, agg)
+	if description != "" {
+		mpb.add(agg, description)
+	} else {
+		mpb.add(agg)
+	}
+	mpb.guard.Unlock()
+
+	mpb.renderAll()
+	return agg
+}
+
+// applyAggregateSums recomputes every aggregate bar's max and current value
+// from its current children and applies the result. Callers must not hold
+// mpb.guard: it takes that lock itself, and calling it from a child bar's own
+// Write callback while still holding it (e.g. via a deferred unlock) would
+// deadlock against the aggregate's own Write, which takes the same lock.
+//
+// It is called both synchronously from renderAll (AddAfter/Remove/Sort/
+// AddAggregate/the refresh tick) and asynchronously from backgroundLoop, off
+// the goroutine driving a child bar's own write (see multiProgressBarWriter.
+// Write and the aggregateDirty channel). mpb.aggregateMu serializes those two
+// call paths against each other: progressbar.ProgressBar.ChangeMax64 mutates
+// the bar's config without taking its own lock, so two goroutines calling it
+// on the same aggregate at once is a real data race, not just a logical
+// ordering issue.
+func (mpb *MultiProgressBar) applyAggregateSums() {
+	mpb.aggregateMu.Lock()
+	defer mpb.aggregateMu.Unlock()
+
+	mpb.guard.Lock()
+	maxSum, curSum := mpb.sumChildrenLocked()
+	aggs := append([]*progressbar.ProgressBar(nil), mpb.aggregates...)
+	mpb.guard.Unlock()
+
+	for _, agg := range aggs {
+		agg.ChangeMax64(maxSum)
+		agg.Set64(curSum)
+	}
+}
+
+// sumChildrenLocked returns the combined max and current value of every bar
+// that isn't itself an aggregate, or 0, 0 when no aggregate is registered.
+// Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) sumChildrenLocked() (maxSum, curSum int64) {
+	if len(mpb.aggregates) == 0 {
+		return 0, 0
+	}
+	for _, b := range mpb.bars {
+		if mpb.isAggregateLocked(b) {
+			continue
+		}
+		maxSum += b.GetMax64()
+		curSum += int64(b.State().CurrentBytes)
+	}
+	return maxSum, curSum
+}
+
+// isAggregateLocked reports whether b was added via AddAggregate. Callers
+// must hold mpb.guard.
+func (mpb *MultiProgressBar) isAggregateLocked(b *progressbar.ProgressBar) bool {
+	for _, agg := range mpb.aggregates {
+		if agg == b {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAggregateLocked drops pBar from the aggregate list, if present.
+// Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) removeAggregateLocked(pBar *progressbar.ProgressBar) {
+	for i, agg := range mpb.aggregates {
+		if agg == pBar {
+			mpb.aggregates = append(mpb.aggregates[:i], mpb.aggregates[i+1:]...)
+			return
+		}
+	}
+}
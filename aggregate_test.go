@@ -0,0 +1,70 @@
+package multiprogressbar
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+func TestApplyAggregateSums(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+
+	// Set the children up before registering the aggregate: a child bar's
+	// write only signals aggregateDirty once mpb.aggregates is non-empty, so
+	// these Set64 calls never trigger the async resync. The aggregate is added
+	// directly (rather than via AddAggregate) to skip its renderAll call,
+	// which would RenderBlank every bar including a and b, re-triggering an
+	// async resync on backgroundLoop that would race the read below.
+	a := mpb.Add(progressbar.NewOptions64(100))
+	b := mpb.Add(progressbar.NewOptions64(50))
+	a.Set64(10)
+	b.Set64(5)
+
+	agg := progressbar.NewOptions64(0)
+	mpb.guard.Lock()
+	mpb.aggregates = append(mpb.aggregates, agg)
+	mpb.add(agg)
+	mpb.guard.Unlock()
+
+	mpb.applyAggregateSums()
+
+	if got, want := agg.GetMax64(), int64(150); got != want {
+		t.Fatalf("aggregate max = %d, want %d", got, want)
+	}
+	if got, want := int64(agg.State().CurrentBytes), int64(15); got != want {
+		t.Fatalf("aggregate current = %d, want %d", got, want)
+	}
+}
+
+// TestApplyAggregateSumsConcurrent reproduces the two call paths that reach
+// applyAggregateSums: the synchronous one from renderAll (AddAfter/Remove/
+// Sort/AddAggregate/the refresh tick) and the asynchronous one driven by a
+// child write (multiProgressBarWriter.Write -> backgroundLoop). Run under
+// -race, this catches a regression to the unserialized version that raced on
+// progressbar.ProgressBar.ChangeMax64. It only stresses the two call paths
+// against each other; it does not assert on the aggregate's final state,
+// since a.Add64 also triggers backgroundLoop resyncs asynchronously and
+// reading agg directly here would race against those in-flight calls too.
+func TestApplyAggregateSumsConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+	mpb.AddAggregate("")
+	a := mpb.Add(progressbar.NewOptions64(100))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Add64(1)
+		}()
+		go func() {
+			defer wg.Done()
+			mpb.applyAggregateSums()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,24 @@
+package multiprogressbar
+
+import "testing"
+
+func TestIsBlankRender(t *testing.T) {
+	cases := []struct {
+		name string
+		p    []byte
+		want bool
+	}{
+		{"empty", []byte(""), true},
+		{"carriage return only", []byte("\r"), true},
+		{"spaces after carriage return", []byte("\r                                  \r"), true},
+		{"content", []byte("\rtest  10% |...|  [0s:0s]\n"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isBlankRender(tc.p); got != tc.want {
+				t.Fatalf("isBlankRender(%q) = %v, want %v", tc.p, got, tc.want)
+			}
+		})
+	}
+}
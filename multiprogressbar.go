@@ -2,20 +2,50 @@ package multiprogressbar
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
 )
 
+// defaultFallbackThrottle bounds how often a bar may print a new line when
+// falling back to non-TTY rendering.
+const defaultFallbackThrottle = 200 * time.Millisecond
+
 // MultiProgressBar manages multiple progress bars.
 type MultiProgressBar struct {
-	curLine int
-	bars    []*progressbar.ProgressBar
-	guard   sync.Mutex
-	output  *bufio.Writer
+	curLine     int
+	bars        []*progressbar.ProgressBar
+	guard       sync.Mutex
+	output      *bufio.Writer
+	rawOutput   io.Writer
+	refreshRate time.Duration
+	closeCh     chan struct{}
+	closeOnce   sync.Once
+
+	forceTTY         *bool
+	isTTY            bool
+	fallbackThrottle time.Duration
+	fallbackLast     map[*progressbar.ProgressBar]time.Time
+
+	termWidth int
+
+	aggregates  []*progressbar.ProgressBar
+	aggregateMu sync.Mutex
+
+	descriptions map[*progressbar.ProgressBar]string
+
+	sinks      []ProgressSink
+	sinkEvents chan sinkEvent
+
+	aggregateDirty chan struct{}
 }
 
 // New creates a new MultiProgressBar with default options.
@@ -26,30 +56,254 @@ func New() *MultiProgressBar {
 // NewOptions creates a new MultiProgressBar with the provided options.
 func NewOptions(options ...Option) *MultiProgressBar {
 	mpb := &MultiProgressBar{
-		curLine: 0,
-		bars:    []*progressbar.ProgressBar{},
-		guard:   sync.Mutex{},
-		output:  bufio.NewWriter(os.Stdout),
+		curLine:          0,
+		bars:             []*progressbar.ProgressBar{},
+		guard:            sync.Mutex{},
+		output:           bufio.NewWriter(os.Stdout),
+		rawOutput:        os.Stdout,
+		closeCh:          make(chan struct{}),
+		fallbackThrottle: defaultFallbackThrottle,
+		fallbackLast:     map[*progressbar.ProgressBar]time.Time{},
+		descriptions:     map[*progressbar.ProgressBar]string{},
+		sinkEvents:       make(chan sinkEvent, 64),
+		aggregateDirty:   make(chan struct{}, 1),
 	}
 	for _, opt := range options {
 		opt(mpb)
 	}
+	if mpb.forceTTY != nil {
+		mpb.isTTY = *mpb.forceTTY
+	} else {
+		mpb.isTTY = isTerminal(mpb.rawOutput)
+	}
+	if mpb.isTTY {
+		mpb.watchResize()
+	}
+	if mpb.refreshRate > 0 {
+		go mpb.renderLoop()
+	}
+	go mpb.backgroundLoop()
 	return mpb
 }
 
-// Add adds a progress bar to the MultiProgressBar.
+// backgroundLoop drains sink fan-out and aggregate-resync work off the
+// goroutine that drives a child bar's own writes. dispatchSink and
+// applyAggregateSums both call back into a progressbar.ProgressBar's locked
+// API (State, GetMax64, ChangeMax64, Set64, IsFinished); applyAggregateSums
+// in particular sums every *other* child's state, including the one whose
+// Write triggered it, so running it synchronously inside that Write would
+// try to re-acquire that same child's lock from the goroutine already
+// holding it via Add64/Set64. Running here, on a dedicated goroutine, just
+// blocks briefly instead. Runs until Close is called.
+func (mpb *MultiProgressBar) backgroundLoop() {
+	for {
+		select {
+		case <-mpb.closeCh:
+			return
+		case ev := <-mpb.sinkEvents:
+			mpb.dispatchSink(ev)
+		case <-mpb.aggregateDirty:
+			mpb.applyAggregateSums()
+		}
+	}
+}
+
+// isTerminal reports whether w is a terminal that supports cursor movement.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Add adds a progress bar to the MultiProgressBar. description, if given, is
+// reported to sinks alongside the bar's other progress fields: it can't be
+// read back off pBar itself, since progressbar.ProgressBar exposes no
+// description getter.
 // This changes the writer of the progress bar. Do not change the writer afterwards!
-// Not thread safe.
+// Thread safe.
 // Returns the added progress bar.
-func (mpb *MultiProgressBar) Add(pBar *progressbar.ProgressBar) *progressbar.ProgressBar {
+func (mpb *MultiProgressBar) Add(pBar *progressbar.ProgressBar, description ...string) *progressbar.ProgressBar {
+	mpb.guard.Lock()
+	defer mpb.guard.Unlock()
+	return mpb.add(pBar, description...)
+}
+
+// AddAfter inserts newBar directly below existing in the bar stack.
+// description is handled exactly as in Add.
+// Thread safe. Returns newBar.
+// If existing is not currently managed by mpb, newBar is appended at the bottom.
+func (mpb *MultiProgressBar) AddAfter(existing, newBar *progressbar.ProgressBar, description ...string) *progressbar.ProgressBar {
+	mpb.guard.Lock()
+	progressbar.OptionSetWriter(&multiProgressBarWriter{
+		MultiProgressBar: mpb,
+		bar:              newBar,
+	})(newBar)
+	if len(description) > 0 {
+		mpb.descriptions[newBar] = description[0]
+	}
+
+	at := mpb.indexOf(existing)
+	if at == -1 {
+		mpb.bars = append(mpb.bars, newBar)
+	} else {
+		mpb.bars = append(mpb.bars, nil)
+		copy(mpb.bars[at+2:], mpb.bars[at+1:])
+		mpb.bars[at+1] = newBar
+	}
+	mpb.guard.Unlock()
+
+	mpb.renderAll()
+	return newBar
+}
+
+// add appends pBar to the bar stack, recording description (if given) for
+// sinks. Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) add(pBar *progressbar.ProgressBar, description ...string) *progressbar.ProgressBar {
 	progressbar.OptionSetWriter(&multiProgressBarWriter{
 		MultiProgressBar: mpb,
-		idx:              len(mpb.bars),
+		bar:              pBar,
 	})(pBar)
+	if len(description) > 0 {
+		mpb.descriptions[pBar] = description[0]
+	}
 	mpb.bars = append(mpb.bars, pBar)
 	return pBar
 }
 
+// indexOf returns the current position of pBar in the bar stack, or -1 if it
+// isn't managed by mpb. Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) indexOf(pBar *progressbar.ProgressBar) int {
+	for i, b := range mpb.bars {
+		if b == pBar {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove removes pBar from the bar stack, erasing its line so no stale text
+// is left behind, and shifts every bar below it up by one row.
+// Thread safe. Returns an error if pBar is not managed by mpb.
+func (mpb *MultiProgressBar) Remove(pBar *progressbar.ProgressBar) error {
+	mpb.guard.Lock()
+
+	at := mpb.indexOf(pBar)
+	if at == -1 {
+		mpb.guard.Unlock()
+		return fmt.Errorf("multiprogressbar: bar not found")
+	}
+
+	if mpb.isTTY {
+		if _, err := mpb.move(len(mpb.bars)-1, mpb.output); err != nil {
+			mpb.guard.Unlock()
+			return err
+		}
+		if _, err := fmt.Fprint(mpb.output, "\r\033[2K"); err != nil {
+			mpb.guard.Unlock()
+			return err
+		}
+		mpb.curLine = len(mpb.bars) - 1
+	}
+
+	delete(mpb.fallbackLast, pBar)
+	delete(mpb.descriptions, pBar)
+	mpb.bars = append(mpb.bars[:at], mpb.bars[at+1:]...)
+	mpb.removeAggregateLocked(pBar)
+	err := mpb.output.Flush()
+	mpb.guard.Unlock()
+
+	mpb.renderAll()
+	return err
+}
+
+// Sort reorders the bar stack in place using less and redraws it.
+// Thread safe.
+func (mpb *MultiProgressBar) Sort(less func(a, b *progressbar.ProgressBar) bool) {
+	mpb.guard.Lock()
+	sort.SliceStable(mpb.bars, func(i, j int) bool {
+		return less(mpb.bars[i], mpb.bars[j])
+	})
+	mpb.guard.Unlock()
+
+	mpb.renderAll()
+}
+
+// renderAll resyncs every aggregate bar against its current children, then
+// redraws every bar in its current stack order. It must not be called while
+// mpb.guard is held: each bar's render triggers a Write on its
+// multiProgressBarWriter, which takes mpb.guard itself.
+func (mpb *MultiProgressBar) renderAll() {
+	mpb.applyAggregateSums()
+
+	mpb.guard.Lock()
+	bars := append([]*progressbar.ProgressBar(nil), mpb.bars...)
+	mpb.guard.Unlock()
+
+	for _, pbar := range bars {
+		pbar.RenderBlank()
+	}
+}
+
+// renderLoop periodically redraws every bar so bars keep advancing (e.g. the
+// elapsed time in the ETA) even when a bar's own state hasn't changed. It
+// runs until Close is called, which happens from End/Finish.
+func (mpb *MultiProgressBar) renderLoop() {
+	ticker := time.NewTicker(mpb.refreshRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-mpb.closeCh:
+			return
+		case <-ticker.C:
+			mpb.renderAll()
+		}
+	}
+}
+
+// Close stops the background render goroutine started when OptionRefreshRate
+// is used. It is safe to call multiple times and is called automatically by
+// End and Finish.
+func (mpb *MultiProgressBar) Close() {
+	mpb.closeOnce.Do(func() {
+		close(mpb.closeCh)
+	})
+}
+
+// Wait blocks until every managed bar reports IsFinished, or until ctx is
+// done, whichever happens first.
+func (mpb *MultiProgressBar) Wait(ctx context.Context) error {
+	pollRate := mpb.refreshRate
+	if pollRate <= 0 {
+		pollRate = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(pollRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if mpb.allFinished() {
+				return nil
+			}
+		}
+	}
+}
+
+// allFinished reports whether every managed bar is finished.
+func (mpb *MultiProgressBar) allFinished() bool {
+	mpb.guard.Lock()
+	defer mpb.guard.Unlock()
+	for _, pbar := range mpb.bars {
+		if !pbar.IsFinished() {
+			return false
+		}
+	}
+	return true
+}
+
 // Get returns the progressbar.ProgressBar at the given index.
 // Panics if the index does not exist.
 func (mpb *MultiProgressBar) Get(idx int) *progressbar.ProgressBar {
@@ -84,16 +338,62 @@ func (mpb *MultiProgressBar) Finish() error {
 	return mpb.End()
 }
 
-// End moves the cursor to the end of the progress bars.
+// End moves the cursor to the end of the progress bars and stops the
+// background render goroutine, if any.
 // Not thread safe.
 func (mpb *MultiProgressBar) End() error {
-	_, err := mpb.move(len(mpb.bars), mpb.output)
-	if err != nil {
-		return err
+	defer mpb.Close()
+	if mpb.isTTY {
+		if _, err := mpb.move(len(mpb.bars), mpb.output); err != nil {
+			return err
+		}
 	}
 	return mpb.output.Flush()
 }
 
+// Write implements io.Writer so a logger can write directly to the bar
+// stack: it moves to the top-most bar's row, clears from there to the end of
+// the screen, writes p, then redraws every bar below it.
+// Thread safe.
+func (mpb *MultiProgressBar) Write(p []byte) (int, error) {
+	mpb.guard.Lock()
+	if mpb.isTTY {
+		if _, err := mpb.move(0, mpb.output); err != nil {
+			mpb.guard.Unlock()
+			return 0, err
+		}
+		if _, err := fmt.Fprint(mpb.output, "\r\033[J"); err != nil {
+			mpb.guard.Unlock()
+			return 0, err
+		}
+	}
+	n, err := mpb.output.Write(p)
+	if err == nil {
+		err = mpb.output.Flush()
+	}
+	mpb.guard.Unlock()
+	if err != nil {
+		return n, err
+	}
+
+	mpb.renderAll()
+	return n, nil
+}
+
+// Bprintln formats using the default formats for its operands, appends a
+// newline, and writes the result above the bar stack without disturbing it.
+func (mpb *MultiProgressBar) Bprintln(a ...interface{}) error {
+	_, err := fmt.Fprintln(mpb, a...)
+	return err
+}
+
+// Bprintf formats according to format and writes the result above the bar
+// stack without disturbing it. Unlike Bprintln it does not append a newline.
+func (mpb *MultiProgressBar) Bprintf(format string, a ...interface{}) error {
+	_, err := fmt.Fprintf(mpb, format, a...)
+	return err
+}
+
 // move moves the cursor to the beginning of the current progress bar.
 func (mpb *MultiProgressBar) move(id int, writer io.Writer) (int, error) {
 	bias := mpb.curLine - id
@@ -115,22 +415,112 @@ type Option func(p *MultiProgressBar)
 // Behavior is undefined if called while using the MultiProgressBar.
 func OptionSetWriter(writer io.Writer) Option {
 	return func(mpb *MultiProgressBar) {
+		mpb.rawOutput = writer
 		mpb.output = bufio.NewWriter(writer)
 	}
 }
 
+// OptionForceTTY overrides TTY auto-detection: true always renders with
+// cursor movement, false always uses the non-TTY line-oriented fallback.
+func OptionForceTTY(tty bool) Option {
+	return func(mpb *MultiProgressBar) {
+		mpb.forceTTY = &tty
+	}
+}
+
+// OptionRefreshRate starts a background goroutine that redraws every bar
+// every d, so bars keep advancing even between writes. A zero duration (the
+// default) leaves rendering driven purely by bar writes.
+func OptionRefreshRate(d time.Duration) Option {
+	return func(mpb *MultiProgressBar) {
+		mpb.refreshRate = d
+	}
+}
+
 // multiProgressBarWriter is an io.Writer wrapper to know which progress bar wants to write.
+// It tracks the bar itself rather than a fixed index so writes still land on
+// the right row after AddAfter, Remove or Sort have moved bars around.
 type multiProgressBarWriter struct {
 	*MultiProgressBar
-	idx int
+	bar *progressbar.ProgressBar
 }
 
 func (lw *multiProgressBarWriter) Write(p []byte) (n int, err error) {
+	if isBlankRender(p) {
+		// progressbar.render() issues a separate space-fill "clear" write
+		// ahead of its real content write whenever useANSICodes is false
+		// (the default). Treating it as a write of its own would throttle
+		// away the content write that immediately follows it, and would
+		// fan it out to sinks as a second, spurious update.
+		return len(p), nil
+	}
 	lw.guard.Lock()
 	defer lw.guard.Unlock()
-	n, err = lw.move(lw.idx, lw.output)
+	idx := lw.indexOf(lw.bar)
+	if idx == -1 {
+		// The bar was removed concurrently with this write; drop it.
+		return len(p), nil
+	}
+	if lw.termWidth > 0 {
+		// Applied here, from inside the same lw.guard-protected section that
+		// already serializes this bar's own write, rather than from resize's
+		// SIGWINCH goroutine directly: progressbar.OptionSetWidth mutates the
+		// bar's config without taking its own lock, so setting it from a
+		// goroutine other than the one driving the bar's writes would race
+		// render's read of that same config. A bar that never writes again
+		// after a resize won't pick up the new width, which is an acceptable
+		// trade-off for not needing a lock the library doesn't export.
+		progressbar.OptionSetWidth(lw.termWidth)(lw.bar)
+	}
+	lw.fanOutLocked(idx, lw.bar)
+	if len(lw.aggregates) > 0 && !lw.isAggregateLocked(lw.bar) {
+		// Only signal, never call applyAggregateSums directly here: it sums
+		// every other child's state, including (once this write's trigger
+		// is handled) this very bar's own, and doing that while this bar's
+		// own Add64/Set64 still holds its lock on this goroutine would
+		// deadlock re-acquiring it. backgroundLoop picks the signal up on
+		// its own goroutine instead.
+		select {
+		case lw.aggregateDirty <- struct{}{}:
+		default:
+			// A resync is already pending; this write will be covered by it.
+		}
+	}
+	if !lw.isTTY {
+		return lw.writeFallbackLocked(lw.bar, p)
+	}
+	n, err = lw.move(idx, lw.output)
 	if err != nil {
 		return n, err
 	}
 	return lw.output.Write(p)
 }
+
+// isBlankRender reports whether p is the space-fill "clear" write that
+// progressbar.render() issues ahead of its real content write, as opposed to
+// the content write itself.
+func isBlankRender(p []byte) bool {
+	return len(bytes.TrimSpace(bytes.TrimLeft(p, "\r"))) == 0
+}
+
+// writeFallbackLocked prints a bar's latest rendered line on its own row,
+// throttled to at most once per fallbackThrottle, for non-TTY outputs where
+// cursor movement would corrupt the stream (e.g. piped or redirected to a
+// file/CI system). Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) writeFallbackLocked(bar *progressbar.ProgressBar, p []byte) (int, error) {
+	now := time.Now()
+	// bar.IsFinished() must not be called here: it would re-acquire bar's
+	// own lock from inside the Write callback that bar's Add/Set already
+	// holds it for, deadlocking the caller.
+	if last, ok := mpb.fallbackLast[bar]; ok && now.Sub(last) < mpb.fallbackThrottle {
+		return len(p), nil
+	}
+	mpb.fallbackLast[bar] = now
+	if _, err := mpb.output.Write(p); err != nil {
+		return 0, err
+	}
+	if _, err := mpb.output.WriteString("\n"); err != nil {
+		return 0, err
+	}
+	return len(p), mpb.output.Flush()
+}
@@ -0,0 +1,90 @@
+package multiprogressbar
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+func TestAddAfter(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+
+	a := mpb.Add(progressbar.NewOptions64(100))
+	c := mpb.Add(progressbar.NewOptions64(100))
+	b := mpb.AddAfter(a, progressbar.NewOptions64(100))
+
+	if got, want := mpb.BarCount(), 3; got != want {
+		t.Fatalf("BarCount() = %d, want %d", got, want)
+	}
+	if mpb.Get(0) != a || mpb.Get(1) != b || mpb.Get(2) != c {
+		t.Fatalf("AddAfter did not insert directly below existing")
+	}
+}
+
+func TestAddAfterUnknownExisting(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+
+	a := mpb.Add(progressbar.NewOptions64(100))
+	unmanaged := progressbar.NewOptions64(100)
+	b := mpb.AddAfter(unmanaged, progressbar.NewOptions64(100))
+
+	if got, want := mpb.BarCount(), 2; got != want {
+		t.Fatalf("BarCount() = %d, want %d", got, want)
+	}
+	if mpb.Get(0) != a || mpb.Get(1) != b {
+		t.Fatalf("AddAfter with an unmanaged existing bar should append at the bottom")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+
+	a := mpb.Add(progressbar.NewOptions64(100))
+	b := mpb.Add(progressbar.NewOptions64(100))
+	c := mpb.Add(progressbar.NewOptions64(100))
+
+	if err := mpb.Remove(b); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if got, want := mpb.BarCount(), 2; got != want {
+		t.Fatalf("BarCount() = %d, want %d", got, want)
+	}
+	if mpb.Get(0) != a || mpb.Get(1) != c {
+		t.Fatalf("Remove did not shift the remaining bars up")
+	}
+}
+
+func TestRemoveUnknownBar(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+	mpb.Add(progressbar.NewOptions64(100))
+
+	if err := mpb.Remove(progressbar.NewOptions64(100)); err == nil {
+		t.Fatal("Remove of an unmanaged bar should return an error")
+	}
+}
+
+func TestSort(t *testing.T) {
+	var buf bytes.Buffer
+	mpb := NewOptions(OptionSetWriter(&buf), OptionForceTTY(false))
+
+	a := mpb.Add(progressbar.NewOptions64(100))
+	b := mpb.Add(progressbar.NewOptions64(100))
+	c := mpb.Add(progressbar.NewOptions64(100))
+	a.Set64(30)
+	b.Set64(10)
+	c.Set64(20)
+
+	mpb.Sort(func(x, y *progressbar.ProgressBar) bool {
+		return x.State().CurrentBytes < y.State().CurrentBytes
+	})
+
+	if mpb.Get(0) != b || mpb.Get(1) != c || mpb.Get(2) != a {
+		t.Fatalf("Sort did not reorder the bar stack by the given comparator")
+	}
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package multiprogressbar
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// watchResize installs a SIGWINCH handler that re-queries the terminal width
+// on every resize and applies it to each managed bar. It is only called when
+// mpb.isTTY, and stops itself when mpb.closeCh is closed.
+func (mpb *MultiProgressBar) watchResize() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-mpb.closeCh:
+				return
+			case <-sigCh:
+				mpb.resize()
+			}
+		}
+	}()
+}
+
+// resize re-queries the terminal width and records it for every managed bar.
+// It does not call progressbar.OptionSetWidth itself: that would mutate a
+// bar's config from this signal-handling goroutine while a caller's own
+// goroutine might concurrently be calling Add/Set on the same bar, racing
+// progressbar's unlocked config read in render. Recording the width here and
+// applying it lazily, from inside multiProgressBarWriter.Write's existing
+// per-bar lock, keeps every config mutation on the goroutine that drives that
+// bar's own writes.
+func (mpb *MultiProgressBar) resize() {
+	f, ok := mpb.rawOutput.(*os.File)
+	if !ok {
+		return
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return
+	}
+
+	mpb.guard.Lock()
+	mpb.termWidth = width
+	mpb.guard.Unlock()
+
+	mpb.renderAll()
+}
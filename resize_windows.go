@@ -0,0 +1,6 @@
+//go:build windows
+
+package multiprogressbar
+
+// watchResize is a no-op on Windows, which has no SIGWINCH equivalent.
+func (mpb *MultiProgressBar) watchResize() {}
@@ -0,0 +1,199 @@
+package multiprogressbar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Event is a snapshot of a single bar's progress, passed to every
+// registered ProgressSink on each update.
+type Event struct {
+	Description string
+	Current     int64
+	Total       int64
+	Rate        float64
+	ETA         float64
+}
+
+// ProgressSink receives progress events for bars managed by a
+// MultiProgressBar, in addition to whatever is rendered to the terminal.
+// Implementations must be safe to call from the background goroutine that
+// drives the fan-out (see MultiProgressBar.backgroundLoop).
+type ProgressSink interface {
+	OnUpdate(barIdx int, ev Event)
+	OnFinish(barIdx int)
+}
+
+// OptionAddSink registers sink to receive every child bar update alongside
+// the terminal writer.
+func OptionAddSink(sink ProgressSink) Option {
+	return func(mpb *MultiProgressBar) {
+		mpb.sinks = append(mpb.sinks, sink)
+	}
+}
+
+// sinkEvent records which bar wrote, to be turned into an Event off the
+// goroutine that triggered the write.
+type sinkEvent struct {
+	idx         int
+	bar         *progressbar.ProgressBar
+	description string
+}
+
+// fanOutLocked queues bar's update for every registered sink. It must not
+// read bar's state itself: it runs inside bar's own Write callback, and
+// bar.State() would try to re-acquire the lock that bar's Add/Set is
+// already holding while calling into Write, deadlocking the caller. The
+// actual state snapshot happens later, in dispatchSink, off that goroutine.
+// description is captured here, rather than in dispatchSink, because it
+// comes from mpb.descriptions (populated at Add/AddAfter/AddAggregate time)
+// which callers are already required to hold mpb.guard for.
+// Callers must hold mpb.guard.
+func (mpb *MultiProgressBar) fanOutLocked(idx int, bar *progressbar.ProgressBar) {
+	if len(mpb.sinks) == 0 {
+		return
+	}
+	select {
+	case mpb.sinkEvents <- sinkEvent{idx: idx, bar: bar, description: mpb.descriptions[bar]}:
+	default:
+		// A sink is behind; drop this update rather than block rendering.
+	}
+}
+
+// dispatchSink builds an Event for ev and forwards it to every sink. It must
+// run on backgroundLoop's goroutine, never on the goroutine inside a bar's
+// own Write callback.
+func (mpb *MultiProgressBar) dispatchSink(ev sinkEvent) {
+	mpb.guard.Lock()
+	sinks := append([]ProgressSink(nil), mpb.sinks...)
+	mpb.guard.Unlock()
+	if len(sinks) == 0 {
+		return
+	}
+
+	state := ev.bar.State()
+	event := Event{
+		Description: ev.description,
+		Current:     int64(state.CurrentBytes),
+		Total:       ev.bar.GetMax64(),
+		Rate:        state.KBsPerSecond,
+		ETA:         state.SecondsLeft,
+	}
+	finished := ev.bar.IsFinished()
+	for _, sink := range sinks {
+		sink.OnUpdate(ev.idx, event)
+		if finished {
+			sink.OnFinish(ev.idx)
+		}
+	}
+}
+
+// JSONLineSink is a ProgressSink that writes one JSON object per update,
+// useful for piping into another process or a web UI.
+type JSONLineSink struct {
+	w io.Writer
+}
+
+// NewJSONLineSink returns a JSONLineSink that writes to w.
+func NewJSONLineSink(w io.Writer) *JSONLineSink {
+	return &JSONLineSink{w: w}
+}
+
+type jsonLineEvent struct {
+	Idx         int     `json:"idx"`
+	Description string  `json:"description"`
+	Current     int64   `json:"current"`
+	Total       int64   `json:"total"`
+	Rate        float64 `json:"rate"`
+	ETA         float64 `json:"eta"`
+}
+
+// OnUpdate writes ev as a single JSON line.
+func (s *JSONLineSink) OnUpdate(idx int, ev Event) {
+	line, err := json.Marshal(jsonLineEvent{
+		Idx:         idx,
+		Description: ev.Description,
+		Current:     ev.Current,
+		Total:       ev.Total,
+		Rate:        ev.Rate,
+		ETA:         ev.ETA,
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(s.w, string(line))
+}
+
+// OnFinish is a no-op; the final OnUpdate already carries the finished state.
+func (s *JSONLineSink) OnFinish(idx int) {}
+
+// DockerSink is a ProgressSink that emits one Docker-style progress object
+// per update, for compatibility with tools that already parse that format.
+type DockerSink struct {
+	w    io.Writer
+	idFn func(idx int) string
+}
+
+// NewDockerSink returns a DockerSink that writes to w. If idFn is nil, bars
+// are identified by their index formatted as a string.
+func NewDockerSink(w io.Writer, idFn func(idx int) string) *DockerSink {
+	return &DockerSink{w: w, idFn: idFn}
+}
+
+type dockerProgressDetail struct {
+	Current int64 `json:"current"`
+	Total   int64 `json:"total"`
+}
+
+type dockerEvent struct {
+	ID             string               `json:"id"`
+	Status         string               `json:"status"`
+	ProgressDetail dockerProgressDetail `json:"progressDetail"`
+}
+
+// OnUpdate writes ev as a single Docker-style progress object. status is
+// ev.Description when the caller supplied one at Add/AddAfter/AddAggregate
+// time, matching real Docker pull/push output (e.g. "Downloading",
+// "Extracting"); otherwise it falls back to a generic Downloading/Download
+// complete status derived from progress.
+func (s *DockerSink) OnUpdate(idx int, ev Event) {
+	status := ev.Description
+	if status == "" {
+		status = "Downloading"
+		if ev.Total > 0 && ev.Current >= ev.Total {
+			status = "Download complete"
+		}
+	}
+	line, err := json.Marshal(dockerEvent{
+		ID:     s.id(idx),
+		Status: status,
+		ProgressDetail: dockerProgressDetail{
+			Current: ev.Current,
+			Total:   ev.Total,
+		},
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(s.w, string(line))
+}
+
+// OnFinish writes a final "done" status object for idx.
+func (s *DockerSink) OnFinish(idx int) {
+	line, err := json.Marshal(dockerEvent{ID: s.id(idx), Status: "done"})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(s.w, string(line))
+}
+
+// id returns the identifier to use for idx, falling back to its decimal form.
+func (s *DockerSink) id(idx int) string {
+	if s.idFn != nil {
+		return s.idFn(idx)
+	}
+	return fmt.Sprintf("%d", idx)
+}
@@ -0,0 +1,72 @@
+package multiprogressbar
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONLineSinkOnUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLineSink(&buf)
+
+	sink.OnUpdate(2, Event{Description: "layer-1", Current: 5, Total: 10, Rate: 1.5, ETA: 3})
+
+	var got jsonLineEvent
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := jsonLineEvent{Idx: 2, Description: "layer-1", Current: 5, Total: 10, Rate: 1.5, ETA: 3}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !strings.Contains(buf.String(), `"description":"layer-1"`) {
+		t.Fatalf("output should contain the description field: %s", buf.String())
+	}
+}
+
+func TestDockerSinkOnUpdateStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		ev     Event
+		status string
+	}{
+		{"in progress", Event{Current: 5, Total: 10}, "Downloading"},
+		{"complete", Event{Current: 10, Total: 10}, "Download complete"},
+		{"unknown total", Event{Current: 5, Total: 0}, "Downloading"},
+		{"explicit description wins", Event{Description: "Extracting", Current: 5, Total: 10}, "Extracting"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			sink := NewDockerSink(&buf, nil)
+
+			sink.OnUpdate(0, tc.ev)
+
+			var got dockerEvent
+			if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got.Status != tc.status {
+				t.Fatalf("got status %q, want %q", got.Status, tc.status)
+			}
+		})
+	}
+}
+
+func TestDockerSinkOnFinish(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewDockerSink(&buf, func(idx int) string { return "layer-1" })
+
+	sink.OnFinish(0)
+
+	var got dockerEvent
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.ID != "layer-1" || got.Status != "done" {
+		t.Fatalf("got %+v", got)
+	}
+}